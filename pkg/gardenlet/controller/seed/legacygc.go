@@ -0,0 +1,190 @@
+// Copyright (c) 2022 SAP SE or an SAP affiliate company. All rights reserved. This file is licensed under the Apache Software License, v. 2 except as noted otherwise in the LICENSE file
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package seed
+
+import (
+	"context"
+	"fmt"
+
+	gardencorev1beta1 "github.com/gardener/gardener/pkg/apis/core/v1beta1"
+	v1beta1constants "github.com/gardener/gardener/pkg/apis/core/v1beta1/constants"
+	resourcesv1alpha1 "github.com/gardener/gardener/pkg/apis/resources/v1alpha1"
+	versionutils "github.com/gardener/gardener/pkg/utils/version"
+
+	corev1 "k8s.io/api/core/v1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// LegacyObjectEntry describes a single object, or a set of objects selected by label, that an
+// older gardenlet version used to create on seeds but that a newer version no longer needs.
+// Entries are only ever appended, never edited in place, so that an existing entry's
+// IntroducedInVersion keeps describing exactly the gardenlet version that stopped relying on it.
+type LegacyObjectEntry struct {
+	// Name is a short, human-readable identifier for this entry, used in log and event messages.
+	Name string
+	// GVK is the GroupVersionKind of the object(s) to remove.
+	GVK schema.GroupVersionKind
+	// Namespace restricts the lookup to a single namespace; empty means cluster-scoped.
+	Namespace string
+	// ObjectName selects a single object by name. Mutually exclusive with Selector.
+	ObjectName string
+	// Selector selects every object matching these labels. Mutually exclusive with ObjectName.
+	Selector labels.Selector
+	// IntroducedInVersion is the first gardenlet version that no longer creates this object and
+	// is safe to garbage collect it under. Seeds that have not yet been upgraded to at least this
+	// version may still depend on the object, so entries newer than the running gardenlet
+	// version are skipped - this is what makes a downgrade to an older gardenlet safe.
+	IntroducedInVersion string
+}
+
+// legacyObjectGC is the registry of objects created by previous gardenlet versions that newer
+// versions garbage collect once a seed has been upgraded far enough. Appending an entry here is
+// the only thing a component needs to do to have its old objects cleaned up; there must be no
+// hard-coded reference to a removed component's name anywhere else in this package.
+var legacyObjectGC = []LegacyObjectEntry{
+	{
+		Name:                "reversed-vpn-auth-server priority class",
+		GVK:                 schedulingv1.SchemeGroupVersion.WithKind("PriorityClass"),
+		ObjectName:          "reversed-vpn-auth-server",
+		IntroducedInVersion: "1.42.0",
+	},
+	{
+		Name:                "fluent-bit priority class",
+		GVK:                 schedulingv1.SchemeGroupVersion.WithKind("PriorityClass"),
+		ObjectName:          "fluent-bit",
+		IntroducedInVersion: "1.42.0",
+	},
+	{
+		Name:                "reversed-vpn-auth-server managed resource",
+		GVK:                 resourcesv1alpha1.SchemeGroupVersion.WithKind("ManagedResource"),
+		Namespace:           v1beta1constants.GardenNamespace,
+		ObjectName:          "reversed-vpn-auth-server",
+		IntroducedInVersion: "1.42.0",
+	},
+}
+
+// CleanupLegacyPriorityClasses deletes the PriorityClass entries in legacyObjectGC, ignoring the
+// gardenlet version gate. It predates ReconcileLegacyObjects and is kept as a small, explicit
+// entry point for the one migration that does not need version gating.
+func CleanupLegacyPriorityClasses(ctx context.Context, seedClient client.Client) error {
+	for _, entry := range legacyObjectGC {
+		if entry.GVK != schedulingv1.SchemeGroupVersion.WithKind("PriorityClass") {
+			continue
+		}
+		if err := deleteLegacyObjects(ctx, seedClient, entry, false, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReconcileLegacyObjects walks legacyObjectGC and garbage collects every entry whose
+// IntroducedInVersion is at or below gardenletVersion, i.e. every object this running gardenlet
+// version is known to no longer need. Entries introduced in a newer gardenlet version than the
+// one currently running are left untouched, so that a downgrade back to that older version is
+// safe. In dryRun mode nothing is deleted; instead an Event is recorded on seed for every object
+// that would have been removed.
+func ReconcileLegacyObjects(ctx context.Context, seedClient client.Client, recorder record.EventRecorder, seed *gardencorev1beta1.Seed, gardenletVersion string, dryRun bool) error {
+	for _, entry := range legacyObjectGC {
+		if entry.IntroducedInVersion != "" {
+			tooNew, err := versionutils.CompareVersions(gardenletVersion, "<", entry.IntroducedInVersion)
+			if err != nil {
+				return fmt.Errorf("failed to compare gardenlet version %q against entry %q: %w", gardenletVersion, entry.Name, err)
+			}
+			if tooNew {
+				continue
+			}
+		}
+
+		var recordEvent func(message string)
+		if dryRun {
+			recordEvent = func(message string) {
+				recorder.Event(seed, corev1.EventTypeNormal, "LegacyObjectGC", message)
+			}
+		}
+
+		if err := deleteLegacyObjects(ctx, seedClient, entry, dryRun, recordEvent); err != nil {
+			return fmt.Errorf("failed to garbage collect legacy objects for entry %q: %w", entry.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// deleteLegacyObjects resolves entry to the objects it currently matches and either deletes each
+// of them, or (in dryRun mode) reports them via recordEvent without touching them.
+func deleteLegacyObjects(ctx context.Context, seedClient client.Client, entry LegacyObjectEntry, dryRun bool, recordEvent func(message string)) error {
+	objs, err := resolveLegacyObjects(ctx, seedClient, entry)
+	if err != nil {
+		return err
+	}
+
+	for _, obj := range objs {
+		if dryRun {
+			if recordEvent != nil {
+				recordEvent(fmt.Sprintf("Would delete legacy %s %s (%s)", entry.GVK.Kind, client.ObjectKeyFromObject(obj), entry.Name))
+			}
+			continue
+		}
+
+		if err := seedClient.Delete(ctx, obj); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete legacy %s %s: %w", entry.GVK.Kind, client.ObjectKeyFromObject(obj), err)
+		}
+	}
+
+	return nil
+}
+
+// resolveLegacyObjects looks up the object(s) that entry currently refers to, returning an empty
+// slice (not an error) if ObjectName is set but the object is already gone.
+func resolveLegacyObjects(ctx context.Context, seedClient client.Client, entry LegacyObjectEntry) ([]*unstructured.Unstructured, error) {
+	if entry.ObjectName != "" {
+		obj := &unstructured.Unstructured{}
+		obj.SetGroupVersionKind(entry.GVK)
+
+		if err := seedClient.Get(ctx, client.ObjectKey{Namespace: entry.Namespace, Name: entry.ObjectName}, obj); err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("failed to get legacy %s %s/%s: %w", entry.GVK.Kind, entry.Namespace, entry.ObjectName, err)
+		}
+
+		return []*unstructured.Unstructured{obj}, nil
+	}
+
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(schema.GroupVersionKind{Group: entry.GVK.Group, Version: entry.GVK.Version, Kind: entry.GVK.Kind + "List"})
+
+	listOpts := []client.ListOption{client.MatchingLabelsSelector{Selector: entry.Selector}}
+	if entry.Namespace != "" {
+		listOpts = append(listOpts, client.InNamespace(entry.Namespace))
+	}
+
+	if err := seedClient.List(ctx, list, listOpts...); err != nil {
+		return nil, fmt.Errorf("failed to list legacy %s objects: %w", entry.GVK.Kind, err)
+	}
+
+	objs := make([]*unstructured.Unstructured, 0, len(list.Items))
+	for i := range list.Items {
+		objs = append(objs, &list.Items[i])
+	}
+	return objs, nil
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2022 SAP SE or an SAP affiliate company. All rights reserved. This file is licensed under the Apache Software License, v. 2 except as noted otherwise in the LICENSE file
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package seed_test
+
+import (
+	"context"
+
+	gardencorev1beta1 "github.com/gardener/gardener/pkg/apis/core/v1beta1"
+	v1beta1constants "github.com/gardener/gardener/pkg/apis/core/v1beta1/constants"
+	resourcesv1alpha1 "github.com/gardener/gardener/pkg/apis/resources/v1alpha1"
+	"github.com/gardener/gardener/pkg/client/kubernetes"
+	. "github.com/gardener/gardener/pkg/gardenlet/controller/seed"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	schedulingv1 "k8s.io/api/scheduling/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("ReconcileLegacyObjects", func() {
+	var (
+		ctx        context.Context
+		seedClient client.Client
+		seed       *gardencorev1beta1.Seed
+		recorder   *record.FakeRecorder
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		seed = &gardencorev1beta1.Seed{ObjectMeta: v1.ObjectMeta{Name: "my-seed"}}
+		recorder = record.NewFakeRecorder(10)
+
+		seedClient = fakeclient.NewClientBuilder().WithScheme(kubernetes.SeedScheme).WithObjects(
+			&schedulingv1.PriorityClass{ObjectMeta: v1.ObjectMeta{Name: "reversed-vpn-auth-server"}, Value: 1},
+			&schedulingv1.PriorityClass{ObjectMeta: v1.ObjectMeta{Name: "fluent-bit"}, Value: 1},
+			&schedulingv1.PriorityClass{ObjectMeta: v1.ObjectMeta{Name: "random"}, Value: 1},
+			&resourcesv1alpha1.ManagedResource{ObjectMeta: v1.ObjectMeta{Name: "reversed-vpn-auth-server", Namespace: v1beta1constants.GardenNamespace}},
+		).Build()
+	})
+
+	It("should skip entries introduced in a newer gardenlet version than the one running", func() {
+		Expect(ReconcileLegacyObjects(ctx, seedClient, recorder, seed, "1.41.0", false)).To(Succeed())
+
+		priorityClasses := &schedulingv1.PriorityClassList{}
+		Expect(seedClient.List(ctx, priorityClasses)).To(Succeed())
+		Expect(priorityClasses.Items).To(HaveLen(3), "nothing should have been cleaned up for a gardenlet older than the entries' IntroducedInVersion")
+	})
+
+	It("should delete every entry whose IntroducedInVersion has been reached, and nothing else", func() {
+		Expect(ReconcileLegacyObjects(ctx, seedClient, recorder, seed, "1.42.0", false)).To(Succeed())
+
+		priorityClasses := &schedulingv1.PriorityClassList{}
+		Expect(seedClient.List(ctx, priorityClasses)).To(Succeed())
+		Expect(priorityClasses.Items).To(HaveLen(1))
+		Expect(priorityClasses.Items[0].Name).To(Equal("random"))
+
+		managedResources := &resourcesv1alpha1.ManagedResourceList{}
+		Expect(seedClient.List(ctx, managedResources, client.InNamespace(v1beta1constants.GardenNamespace))).To(Succeed())
+		Expect(managedResources.Items).To(BeEmpty())
+	})
+
+	It("should not delete anything and only record events in dry-run mode", func() {
+		Expect(ReconcileLegacyObjects(ctx, seedClient, recorder, seed, "1.42.0", true)).To(Succeed())
+
+		priorityClasses := &schedulingv1.PriorityClassList{}
+		Expect(seedClient.List(ctx, priorityClasses)).To(Succeed())
+		Expect(priorityClasses.Items).To(HaveLen(3), "dry-run must not delete anything")
+
+		Expect(recorder.Events).To(HaveLen(3))
+	})
+})
@@ -0,0 +1,187 @@
+// Copyright (c) 2022 SAP SE or an SAP affiliate company. All rights reserved. This file is licensed under the Apache Software License, v. 2 except as noted otherwise in the LICENSE file
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shootsecret
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	gardencorev1alpha1 "github.com/gardener/gardener/pkg/apis/core/v1alpha1"
+	gardencorev1alpha1helper "github.com/gardener/gardener/pkg/apis/core/v1alpha1/helper"
+	"github.com/gardener/gardener/pkg/controllerutils"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// DefaultBatchWindow is the default delay a shootStateBatcher waits after queuing the first
+// mutation for a given ShootState before flushing it, giving other Secret events for the same
+// shoot namespace a chance to be coalesced into the same patch.
+const DefaultBatchWindow = 2 * time.Second
+
+// batchOp is a single queued mutation to a ShootState's GardenerResourceDataList. For a delete,
+// secret's finalizer is only released once the mutation has actually been patched, so that a
+// failed (or not yet flushed) batch can never release the finalizer while the Secret's data is
+// still in the ShootState. If releasing the finalizer fails after a successful patch, the error
+// is returned and the finalizer stays on the Secret until a later attempt removes it.
+type batchOp struct {
+	upsert     *gardencorev1alpha1.GardenerResourceData
+	deleteName string
+
+	secret          *corev1.Secret
+	removeFinalizer bool
+
+	done chan error
+}
+
+// shootStateBatcher coalesces GardenerResourceDataList mutations for the same ShootState that
+// arrive within Window of each other into a single strategic-merge patch, instead of patching
+// the ShootState once per Secret event. This bounds the write amplification that
+// gardener-resource-manager induced Secret churn would otherwise cause against the garden API
+// server.
+type shootStateBatcher struct {
+	GardenClient client.Client
+	SeedClient   client.Client
+	Window       time.Duration
+
+	mu     sync.Mutex
+	queues map[client.ObjectKey][]*batchOp
+	timers map[client.ObjectKey]*time.Timer
+}
+
+// NewShootStateBatcher returns a shootStateBatcher that flushes the mutations coalesced for a
+// given ShootState window after the first one was queued. A non-positive window falls back to
+// DefaultBatchWindow.
+func NewShootStateBatcher(gardenClient, seedClient client.Client, window time.Duration) *shootStateBatcher {
+	if window <= 0 {
+		window = DefaultBatchWindow
+	}
+
+	return &shootStateBatcher{
+		GardenClient: gardenClient,
+		SeedClient:   seedClient,
+		Window:       window,
+		queues:       map[client.ObjectKey][]*batchOp{},
+		timers:       map[client.ObjectKey]*time.Timer{},
+	}
+}
+
+// Upsert queues data for upsert into the GardenerResourceDataList of the ShootState identified by
+// shootStateKey, and blocks until the batch it ends up in has been flushed.
+func (b *shootStateBatcher) Upsert(ctx context.Context, shootStateKey client.ObjectKey, data *gardencorev1alpha1.GardenerResourceData) error {
+	return b.enqueue(ctx, shootStateKey, &batchOp{upsert: data})
+}
+
+// Delete queues secretName for removal from the GardenerResourceDataList of the ShootState
+// identified by shootStateKey, releasing secret's finalizer once - and only once - that removal
+// has been durably patched, and blocks until the batch it ends up in has been flushed.
+func (b *shootStateBatcher) Delete(ctx context.Context, shootStateKey client.ObjectKey, secret *corev1.Secret, secretName string) error {
+	return b.enqueue(ctx, shootStateKey, &batchOp{deleteName: secretName, secret: secret, removeFinalizer: true})
+}
+
+func (b *shootStateBatcher) enqueue(ctx context.Context, shootStateKey client.ObjectKey, op *batchOp) error {
+	op.done = make(chan error, 1)
+
+	b.mu.Lock()
+	b.queues[shootStateKey] = append(b.queues[shootStateKey], op)
+	if _, scheduled := b.timers[shootStateKey]; !scheduled {
+		b.timers[shootStateKey] = time.AfterFunc(b.Window, func() { b.flush(shootStateKey) })
+	}
+	b.mu.Unlock()
+
+	select {
+	case err := <-op.done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// flush patches shootStateKey's ShootState once with every mutation queued for it, then applies
+// the finalizer changes that depend on that patch having succeeded, and finally wakes up every
+// caller blocked on one of the coalesced ops.
+func (b *shootStateBatcher) flush(shootStateKey client.ObjectKey) {
+	b.mu.Lock()
+	ops := b.queues[shootStateKey]
+	delete(b.queues, shootStateKey)
+	delete(b.timers, shootStateKey)
+	b.mu.Unlock()
+
+	if len(ops) == 0 {
+		return
+	}
+
+	ctx := context.Background()
+	start := time.Now()
+	batchSize.Observe(float64(len(ops)))
+
+	patchErr := b.patchShootState(ctx, shootStateKey, ops)
+	batchFlushDuration.Observe(time.Since(start).Seconds())
+
+	for _, op := range ops {
+		if patchErr != nil {
+			op.done <- patchErr
+			continue
+		}
+		op.done <- b.releaseFinalizerIfNeeded(ctx, op)
+	}
+}
+
+func (b *shootStateBatcher) patchShootState(ctx context.Context, shootStateKey client.ObjectKey, ops []*batchOp) error {
+	log := logf.FromContext(ctx)
+
+	return retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		shootState := &gardencorev1alpha1.ShootState{}
+		if err := b.GardenClient.Get(ctx, shootStateKey, shootState); err != nil {
+			return err
+		}
+
+		patch := client.StrategicMergeFrom(shootState.DeepCopy())
+
+		dataList := gardencorev1alpha1helper.GardenerResourceDataList(shootState.Spec.Gardener)
+		for _, op := range ops {
+			switch {
+			case op.upsert != nil:
+				dataList.Upsert(op.upsert)
+			case op.deleteName != "":
+				dataList.Delete(op.deleteName)
+			}
+		}
+		shootState.Spec.Gardener = dataList
+
+		if err := b.GardenClient.Patch(ctx, shootState, patch); err != nil {
+			if apierrors.IsConflict(err) {
+				batchConflictRetriesTotal.Inc()
+				log.V(1).Info("Conflict while applying batched ShootState patch, retrying", "shootState", shootStateKey)
+			}
+			return err
+		}
+
+		return nil
+	})
+}
+
+func (b *shootStateBatcher) releaseFinalizerIfNeeded(ctx context.Context, op *batchOp) error {
+	if !op.removeFinalizer || !controllerutil.ContainsFinalizer(op.secret, finalizerName) {
+		return nil
+	}
+
+	return controllerutils.RemoveFinalizers(ctx, b.SeedClient, op.secret, finalizerName)
+}
@@ -0,0 +1,139 @@
+// Copyright (c) 2022 SAP SE or an SAP affiliate company. All rights reserved. This file is licensed under the Apache Software License, v. 2 except as noted otherwise in the LICENSE file
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shootsecret
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	gardencorev1alpha1 "github.com/gardener/gardener/pkg/apis/core/v1alpha1"
+	"github.com/gardener/gardener/pkg/client/kubernetes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+var _ = Describe("shootStateBatcher", func() {
+	var (
+		ctx        context.Context
+		shootState *gardencorev1alpha1.ShootState
+		key        client.ObjectKey
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+
+		shootState = &gardencorev1alpha1.ShootState{
+			ObjectMeta: metav1.ObjectMeta{Name: "bar", Namespace: "garden-foo"},
+		}
+		key = client.ObjectKeyFromObject(shootState)
+	})
+
+	It("should coalesce concurrent upserts for the same ShootState into a single patch", func() {
+		gardenClient := fake.NewClientBuilder().WithScheme(kubernetes.GardenScheme).WithObjects(shootState).Build()
+
+		var patchCount int32
+		countingClient := interceptor.NewClient(gardenClient, interceptor.Funcs{
+			Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+				atomic.AddInt32(&patchCount, 1)
+				return c.Patch(ctx, obj, patch, opts...)
+			},
+		})
+
+		batcher := NewShootStateBatcher(countingClient, nil, 50*time.Millisecond)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 5; i++ {
+			i := i
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+
+				err := batcher.Upsert(ctx, key, &gardencorev1alpha1.GardenerResourceData{
+					Name: fmt.Sprintf("secret-%d", i),
+					Type: secretResourceDataType,
+					Data: runtime.RawExtension{Raw: []byte(`{}`)},
+				})
+				Expect(err).NotTo(HaveOccurred())
+			}()
+		}
+		wg.Wait()
+
+		Expect(patchCount).To(Equal(int32(1)), "all 5 concurrent upserts should have been coalesced into a single patch")
+
+		persisted := &gardencorev1alpha1.ShootState{}
+		Expect(gardenClient.Get(ctx, key, persisted)).To(Succeed())
+		Expect(persisted.Spec.Gardener).To(HaveLen(5))
+	})
+
+	It("should retry the batch patch on a resource version conflict", func() {
+		gardenClient := fake.NewClientBuilder().WithScheme(kubernetes.GardenScheme).WithObjects(shootState).Build()
+
+		var attempts int32
+		flakyClient := interceptor.NewClient(gardenClient, interceptor.Funcs{
+			Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+				if atomic.AddInt32(&attempts, 1) == 1 {
+					return apierrors.NewConflict(schema.GroupResource{Group: gardencorev1alpha1.SchemeGroupVersion.Group, Resource: "shootstates"}, obj.GetName(), fmt.Errorf("conflict"))
+				}
+				return c.Patch(ctx, obj, patch, opts...)
+			},
+		})
+
+		retriesBefore := testutil.ToFloat64(batchConflictRetriesTotal)
+
+		batcher := NewShootStateBatcher(flakyClient, nil, 10*time.Millisecond)
+		Expect(batcher.Upsert(ctx, key, &gardencorev1alpha1.GardenerResourceData{
+			Name: "my-secret",
+			Type: secretResourceDataType,
+			Data: runtime.RawExtension{Raw: []byte(`{}`)},
+		})).To(Succeed())
+
+		Expect(testutil.ToFloat64(batchConflictRetriesTotal)).To(Equal(retriesBefore + 1))
+	})
+
+	It("should only release the Secret's finalizer once the batched delete has been patched", func() {
+		shootState.Spec.Gardener = []gardencorev1alpha1.GardenerResourceData{{Name: "my-secret", Type: secretResourceDataType}}
+		gardenClient := fake.NewClientBuilder().WithScheme(kubernetes.GardenScheme).WithObjects(shootState).Build()
+
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-secret", Namespace: "shoot--foo--bar", Finalizers: []string{finalizerName}},
+		}
+		seedClient := fake.NewClientBuilder().WithScheme(kubernetes.SeedScheme).WithObjects(secret).Build()
+
+		batcher := NewShootStateBatcher(gardenClient, seedClient, 10*time.Millisecond)
+		Expect(batcher.Delete(ctx, key, secret, "my-secret")).To(Succeed())
+
+		persistedShootState := &gardencorev1alpha1.ShootState{}
+		Expect(gardenClient.Get(ctx, key, persistedShootState)).To(Succeed())
+		Expect(persistedShootState.Spec.Gardener).To(BeEmpty())
+
+		persistedSecret := &corev1.Secret{}
+		Expect(seedClient.Get(ctx, client.ObjectKeyFromObject(secret), persistedSecret)).To(Succeed())
+		Expect(persistedSecret.Finalizers).To(BeEmpty())
+	})
+})
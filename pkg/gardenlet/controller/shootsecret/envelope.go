@@ -0,0 +1,107 @@
+// Copyright (c) 2022 SAP SE or an SAP affiliate company. All rights reserved. This file is licensed under the Apache Software License, v. 2 except as noted otherwise in the LICENSE file
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shootsecret
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	gardencorev1alpha1 "github.com/gardener/gardener/pkg/apis/core/v1alpha1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// secretResourceDataType is the GardenerResourceData.Type used for the (envelope-encrypted)
+// payload of a shoot Secret that is persisted in a ShootState.
+const secretResourceDataType = "secret"
+
+// EncryptedSecretData is the envelope-encrypted representation of a Secret's `.Data` that is
+// persisted as the `Data` of a "secret" GardenerResourceData entry in a ShootState. It carries
+// enough metadata for a SecretEnvelope to locate the key that was used to produce it again,
+// even after that key has been rotated out as the current one.
+type EncryptedSecretData struct {
+	// KeyID identifies the key (and, for KMS-backed envelopes, its version) that Ciphertext was
+	// encrypted with or, if EncryptedDEK is set, that EncryptedDEK was encrypted with.
+	KeyID string `json:"keyID"`
+	// Algorithm identifies the envelope implementation that produced this payload, e.g.
+	// AlgorithmAESGCM or AlgorithmKMSEnvelope.
+	Algorithm string `json:"algorithm"`
+	// EncryptedDEK is the data encryption key for Ciphertext, itself encrypted under KeyID.
+	// It is only set for envelopes that encrypt with a locally generated, per-secret DEK
+	// rather than directly with the named key (e.g. kmsEnvelope).
+	EncryptedDEK []byte `json:"encryptedDEK,omitempty"`
+	// Ciphertext is the encrypted Secret.Data, marshalled to JSON before encryption.
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// SecretEnvelope encrypts and decrypts the raw Secret.Data bytes before they are persisted in a
+// ShootState, and transparently decrypts them again on read. Implementations are free to choose
+// how the underlying key material is managed (a locally configured key, a KMS provider plugin,
+// ...), but must be able to decrypt payloads produced with any key id they have ever used, so
+// that rotation to a new current key does not strand already-persisted data.
+type SecretEnvelope interface {
+	// Encrypt wraps plaintext (the JSON-marshalled Secret.Data) and returns its
+	// envelope-encrypted representation, tagged with CurrentKeyID.
+	Encrypt(ctx context.Context, plaintext []byte) (*EncryptedSecretData, error)
+	// Decrypt unwraps a payload previously produced by Encrypt, using whichever key it was
+	// encrypted with, and returns the plaintext.
+	Decrypt(ctx context.Context, payload *EncryptedSecretData) ([]byte, error)
+	// CurrentKeyID returns the id of the key that Encrypt currently encrypts new payloads
+	// with. The key rotation loop re-encrypts any GardenerResourceData entry whose stored
+	// key id no longer matches this value.
+	CurrentKeyID() string
+}
+
+// encryptSecretData encrypts data with envelope and marshals the result to a runtime.RawExtension
+// suitable for GardenerResourceData.Data.
+func encryptSecretData(ctx context.Context, envelope SecretEnvelope, data []byte) (runtime.RawExtension, error) {
+	payload, err := envelope.Encrypt(ctx, data)
+	if err != nil {
+		return runtime.RawExtension{}, fmt.Errorf("failed to encrypt secret data: %w", err)
+	}
+
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return runtime.RawExtension{}, fmt.Errorf("failed to marshal encrypted secret data: %w", err)
+	}
+
+	return runtime.RawExtension{Raw: payloadJSON}, nil
+}
+
+// decryptSecretData is the inverse of encryptSecretData: it unmarshals raw as an
+// EncryptedSecretData and decrypts it with envelope.
+func decryptSecretData(ctx context.Context, envelope SecretEnvelope, raw runtime.RawExtension) ([]byte, error) {
+	payload := &EncryptedSecretData{}
+	if err := json.Unmarshal(raw.Raw, payload); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal encrypted secret data: %w", err)
+	}
+
+	plaintext, err := envelope.Decrypt(ctx, payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt secret data (key id %q): %w", payload.KeyID, err)
+	}
+
+	return plaintext, nil
+}
+
+// DecryptGardenerResourceData decrypts the Data of a "secret" GardenerResourceData entry
+// previously written by this controller. It is exported for use by restore code that needs to
+// read a shoot Secret's plaintext `.Data` back out of a ShootState.
+func DecryptGardenerResourceData(ctx context.Context, envelope SecretEnvelope, entry *gardencorev1alpha1.GardenerResourceData) ([]byte, error) {
+	if entry.Type != secretResourceDataType {
+		return nil, fmt.Errorf("gardener resource data entry %q is not of type %q", entry.Name, secretResourceDataType)
+	}
+	return decryptSecretData(ctx, envelope, entry.Data)
+}
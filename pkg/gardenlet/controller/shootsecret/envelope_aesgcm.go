@@ -0,0 +1,124 @@
+// Copyright (c) 2022 SAP SE or an SAP affiliate company. All rights reserved. This file is licensed under the Apache Software License, v. 2 except as noted otherwise in the LICENSE file
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shootsecret
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+)
+
+// AlgorithmAESGCM identifies EncryptedSecretData payloads produced by an aesGCMEnvelope.
+const AlgorithmAESGCM = "aesgcm"
+
+// aesGCMEnvelope implements SecretEnvelope using AES-256-GCM with locally configured data
+// encryption keys (DEKs). It keeps every key it was ever constructed with around (keyed by id)
+// so that it can still decrypt payloads encrypted under a key that has since been rotated out.
+type aesGCMEnvelope struct {
+	currentKeyID string
+	keys         map[string][]byte
+}
+
+// NewAESGCMEnvelope returns a SecretEnvelope that encrypts new payloads with the 32-byte
+// AES-256 key identified by currentKeyID, and can decrypt payloads encrypted with any of the
+// keys in keys (which must include an entry for currentKeyID). keys typically also contains
+// previous keys, so that a rotation from one currentKeyID to another does not break decryption
+// of data that has not been re-encrypted yet.
+func NewAESGCMEnvelope(currentKeyID string, keys map[string][]byte) (SecretEnvelope, error) {
+	if _, ok := keys[currentKeyID]; !ok {
+		return nil, fmt.Errorf("no key configured for current key id %q", currentKeyID)
+	}
+
+	for id, key := range keys {
+		if len(key) != 32 {
+			return nil, fmt.Errorf("key %q must be 32 bytes for AES-256, got %d", id, len(key))
+		}
+	}
+
+	return &aesGCMEnvelope{currentKeyID: currentKeyID, keys: keys}, nil
+}
+
+func (e *aesGCMEnvelope) CurrentKeyID() string {
+	return e.currentKeyID
+}
+
+func (e *aesGCMEnvelope) Encrypt(_ context.Context, plaintext []byte) (*EncryptedSecretData, error) {
+	ciphertext, err := aesGCMSeal(e.keys[e.currentKeyID], plaintext)
+	if err != nil {
+		return nil, err
+	}
+
+	return &EncryptedSecretData{
+		KeyID:      e.currentKeyID,
+		Algorithm:  AlgorithmAESGCM,
+		Ciphertext: ciphertext,
+	}, nil
+}
+
+func (e *aesGCMEnvelope) Decrypt(_ context.Context, payload *EncryptedSecretData) ([]byte, error) {
+	if payload.Algorithm != AlgorithmAESGCM {
+		return nil, fmt.Errorf("unsupported algorithm %q for aesGCMEnvelope", payload.Algorithm)
+	}
+
+	key, ok := e.keys[payload.KeyID]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", payload.KeyID)
+	}
+
+	return aesGCMOpen(key, payload.Ciphertext)
+}
+
+// aesGCMSeal encrypts plaintext with AES-GCM under key, prepending the randomly generated nonce
+// to the returned ciphertext so that aesGCMOpen can recover it again.
+func aesGCMSeal(key, plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AES cipher: %w", err)
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// aesGCMOpen decrypts a ciphertext produced by aesGCMSeal with the same key.
+func aesGCMOpen(key, ciphertext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AES cipher: %w", err)
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+
+	if len(ciphertext) < gcm.NonceSize() {
+		return nil, fmt.Errorf("ciphertext shorter than nonce size")
+	}
+
+	nonce, sealed := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, sealed, nil)
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2022 SAP SE or an SAP affiliate company. All rights reserved. This file is licensed under the Apache Software License, v. 2 except as noted otherwise in the LICENSE file
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shootsecret
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+)
+
+// AlgorithmKMSEnvelope identifies EncryptedSecretData payloads produced by a kmsEnvelope.
+const AlgorithmKMSEnvelope = "kms-envelope"
+
+// KMSClient is the minimal interface a KMS provider plugin must implement to back a
+// kmsEnvelope. Implementations wrap a concrete provider's encrypt/decrypt API (e.g. AWS KMS,
+// GCP Cloud KMS, Vault transit) behind the single key id scheme used by EncryptedSecretData.
+type KMSClient interface {
+	// Encrypt encrypts plaintext (a locally generated DEK) under the key identified by keyID.
+	Encrypt(ctx context.Context, keyID string, plaintext []byte) ([]byte, error)
+	// Decrypt decrypts ciphertext that was previously encrypted under keyID via Encrypt.
+	Decrypt(ctx context.Context, keyID string, ciphertext []byte) ([]byte, error)
+}
+
+// kmsEnvelope implements SecretEnvelope via envelope encryption backed by a KMS provider
+// plugin: a fresh 256-bit DEK is generated per Encrypt call, the plaintext is sealed with that
+// DEK locally (AES-GCM), and only the small DEK itself is sent to the KMS to be wrapped. This
+// keeps the (potentially large) secret payload from ever leaving the cluster.
+type kmsEnvelope struct {
+	client       KMSClient
+	currentKeyID string
+}
+
+// NewKMSEnvelope returns a SecretEnvelope that performs envelope encryption via client, wrapping
+// freshly generated DEKs under currentKeyID. Decrypting a payload encrypted under a different
+// (older) key id is supported transparently, as long as client can still unwrap DEKs for that
+// key id.
+func NewKMSEnvelope(client KMSClient, currentKeyID string) SecretEnvelope {
+	return &kmsEnvelope{client: client, currentKeyID: currentKeyID}
+}
+
+func (e *kmsEnvelope) CurrentKeyID() string {
+	return e.currentKeyID
+}
+
+func (e *kmsEnvelope) Encrypt(ctx context.Context, plaintext []byte) (*EncryptedSecretData, error) {
+	dek := make([]byte, 32)
+	if _, err := rand.Read(dek); err != nil {
+		return nil, fmt.Errorf("failed to generate data encryption key: %w", err)
+	}
+
+	ciphertext, err := aesGCMSeal(dek, plaintext)
+	if err != nil {
+		return nil, err
+	}
+
+	encryptedDEK, err := e.client.Encrypt(ctx, e.currentKeyID, dek)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap data encryption key via KMS: %w", err)
+	}
+
+	return &EncryptedSecretData{
+		KeyID:        e.currentKeyID,
+		Algorithm:    AlgorithmKMSEnvelope,
+		EncryptedDEK: encryptedDEK,
+		Ciphertext:   ciphertext,
+	}, nil
+}
+
+func (e *kmsEnvelope) Decrypt(ctx context.Context, payload *EncryptedSecretData) ([]byte, error) {
+	if payload.Algorithm != AlgorithmKMSEnvelope {
+		return nil, fmt.Errorf("unsupported algorithm %q for kmsEnvelope", payload.Algorithm)
+	}
+
+	dek, err := e.client.Decrypt(ctx, payload.KeyID, payload.EncryptedDEK)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data encryption key via KMS: %w", err)
+	}
+
+	return aesGCMOpen(dek, payload.Ciphertext)
+}
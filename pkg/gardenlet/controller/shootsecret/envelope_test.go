@@ -0,0 +1,180 @@
+// Copyright (c) 2022 SAP SE or an SAP affiliate company. All rights reserved. This file is licensed under the Apache Software License, v. 2 except as noted otherwise in the LICENSE file
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shootsecret_test
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+
+	. "github.com/gardener/gardener/pkg/gardenlet/controller/shootsecret"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// fakeKMS is an in-memory stand-in for a real KMS provider plugin. It "wraps" a DEK by
+// sealing it with the KEK registered for a given key id, so that tests can exercise key
+// rotation without talking to an actual cloud KMS.
+type fakeKMS struct {
+	keks map[string][]byte
+}
+
+func newFakeKMS(keks map[string][]byte) *fakeKMS {
+	return &fakeKMS{keks: keks}
+}
+
+func (f *fakeKMS) Encrypt(_ context.Context, keyID string, plaintext []byte) ([]byte, error) {
+	kek, ok := f.keks[keyID]
+	if !ok {
+		return nil, fmt.Errorf("fakeKMS: unknown key id %q", keyID)
+	}
+	return sealWithKEK(kek, plaintext)
+}
+
+func (f *fakeKMS) Decrypt(_ context.Context, keyID string, ciphertext []byte) ([]byte, error) {
+	kek, ok := f.keks[keyID]
+	if !ok {
+		return nil, fmt.Errorf("fakeKMS: unknown key id %q", keyID)
+	}
+	return openWithKEK(kek, ciphertext)
+}
+
+func sealWithKEK(kek, plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func openWithKEK(kek, ciphertext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce, sealed := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, sealed, nil)
+}
+
+var _ = Describe("SecretEnvelope", func() {
+	var ctx context.Context
+
+	BeforeEach(func() {
+		ctx = context.Background()
+	})
+
+	Describe("aesGCMEnvelope", func() {
+		It("should round-trip plaintext through Encrypt and Decrypt", func() {
+			envelope, err := NewAESGCMEnvelope("key-1", map[string][]byte{"key-1": make([]byte, 32)})
+			Expect(err).NotTo(HaveOccurred())
+
+			payload, err := envelope.Encrypt(ctx, []byte("super secret"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(payload.KeyID).To(Equal("key-1"))
+			Expect(payload.Algorithm).To(Equal(AlgorithmAESGCM))
+
+			plaintext, err := envelope.Decrypt(ctx, payload)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(plaintext).To(Equal([]byte("super secret")))
+		})
+
+		It("should still decrypt payloads encrypted under a previous key after rotation", func() {
+			oldKey := make([]byte, 32)
+			newKey := append(make([]byte, 0, 32), oldKey...)
+			newKey[0] = 1
+
+			oldEnvelope, err := NewAESGCMEnvelope("key-1", map[string][]byte{"key-1": oldKey})
+			Expect(err).NotTo(HaveOccurred())
+			payload, err := oldEnvelope.Encrypt(ctx, []byte("data"))
+			Expect(err).NotTo(HaveOccurred())
+
+			rotatedEnvelope, err := NewAESGCMEnvelope("key-2", map[string][]byte{"key-1": oldKey, "key-2": newKey})
+			Expect(err).NotTo(HaveOccurred())
+
+			plaintext, err := rotatedEnvelope.Decrypt(ctx, payload)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(plaintext).To(Equal([]byte("data")))
+		})
+
+		It("should error when the current key id has no configured key", func() {
+			_, err := NewAESGCMEnvelope("missing", map[string][]byte{"other": make([]byte, 32)})
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should error when decrypting a payload whose key id is unknown", func() {
+			envelope, err := NewAESGCMEnvelope("key-1", map[string][]byte{"key-1": make([]byte, 32)})
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = envelope.Decrypt(ctx, &EncryptedSecretData{KeyID: "unknown", Algorithm: AlgorithmAESGCM, Ciphertext: []byte("x")})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("kmsEnvelope with a fake KMS", func() {
+		It("should round-trip plaintext through Encrypt and Decrypt", func() {
+			kms := newFakeKMS(map[string][]byte{"kms-key-1": make([]byte, 32)})
+			envelope := NewKMSEnvelope(kms, "kms-key-1")
+
+			payload, err := envelope.Encrypt(ctx, []byte("shoot kubeconfig"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(payload.KeyID).To(Equal("kms-key-1"))
+			Expect(payload.Algorithm).To(Equal(AlgorithmKMSEnvelope))
+			Expect(payload.EncryptedDEK).NotTo(BeEmpty())
+
+			plaintext, err := envelope.Decrypt(ctx, payload)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(plaintext).To(Equal([]byte("shoot kubeconfig")))
+		})
+
+		It("should still decrypt payloads encrypted under a previous KMS key id after rotation", func() {
+			kms := newFakeKMS(map[string][]byte{
+				"kms-key-1": make([]byte, 32),
+				"kms-key-2": append(make([]byte, 0, 32), make([]byte, 32)...),
+			})
+
+			oldEnvelope := NewKMSEnvelope(kms, "kms-key-1")
+			payload, err := oldEnvelope.Encrypt(ctx, []byte("data"))
+			Expect(err).NotTo(HaveOccurred())
+
+			rotatedEnvelope := NewKMSEnvelope(kms, "kms-key-2")
+			plaintext, err := rotatedEnvelope.Decrypt(ctx, payload)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(plaintext).To(Equal([]byte("data")))
+		})
+
+		It("should propagate an error from the KMS client on an unknown key id", func() {
+			kms := newFakeKMS(map[string][]byte{"kms-key-1": make([]byte, 32)})
+			envelope := NewKMSEnvelope(kms, "does-not-exist")
+
+			_, err := envelope.Encrypt(ctx, []byte("data"))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,54 @@
+// Copyright (c) 2022 SAP SE or an SAP affiliate company. All rights reserved. This file is licensed under the Apache Software License, v. 2 except as noted otherwise in the LICENSE file
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shootsecret
+
+import (
+	"github.com/gardener/gardener/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	metricsNamespace = "gardenlet"
+	metricsSubsystem = "shootsecret"
+)
+
+var (
+	batchSize = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "batch_size",
+		Help:      "Number of secret events coalesced into a single ShootState batch patch.",
+		Buckets:   prometheus.LinearBuckets(1, 2, 10),
+	})
+
+	batchFlushDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "batch_flush_duration_seconds",
+		Help:      "Time spent flushing a batch of ShootState mutations, including any conflict retries.",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	batchConflictRetriesTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "batch_conflict_retries_total",
+		Help:      "Number of times a batched ShootState patch had to be retried due to a resource version conflict.",
+	})
+)
+
+func init() {
+	metrics.Registry.MustRegister(batchSize, batchFlushDuration, batchConflictRetriesTotal)
+}
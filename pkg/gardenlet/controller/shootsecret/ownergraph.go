@@ -0,0 +1,77 @@
+// Copyright (c) 2022 SAP SE or an SAP affiliate company. All rights reserved. This file is licensed under the Apache Software License, v. 2 except as noted otherwise in the LICENSE file
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shootsecret
+
+import (
+	"context"
+	"fmt"
+
+	gardencorev1alpha1 "github.com/gardener/gardener/pkg/apis/core/v1alpha1"
+	gardencorev1beta1 "github.com/gardener/gardener/pkg/apis/core/v1beta1"
+	v1beta1constants "github.com/gardener/gardener/pkg/apis/core/v1beta1/constants"
+	"github.com/gardener/gardener/pkg/extensions"
+	kutil "github.com/gardener/gardener/pkg/utils/kubernetes"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// ownerChain captures the liveness of the Namespace → Cluster → Shoot → Secret ownership chain
+// that this controller relies on to decide whether removing its finalizer from a Secret can
+// ever orphan data in a ShootState. Each link is resolved top-down so that a NotFound in the
+// middle of the chain (the Cluster/Shoot has been deleted, but the Namespace has not yet been
+// garbage collected) is distinguished from a NotFound at the very top (the Namespace itself is
+// gone) - the only case where nothing could still reference the Secret's data.
+type ownerChain struct {
+	// namespaceExists is false if the Secret's Namespace has already been deleted. When that
+	// happens the Cluster and Shoot that used to live below it in the chain are necessarily gone
+	// too.
+	namespaceExists bool
+	// isShootNamespace is true if the Namespace (when it exists) is labelled as belonging to a
+	// Shoot. Secrets in any other namespace are not of interest to this controller.
+	isShootNamespace bool
+	// shootState is the ShootState owning the Secret's data, or nil if the Cluster/Shoot/
+	// ShootState has already been removed while the Namespace itself has not caught up yet.
+	shootState *gardencorev1alpha1.ShootState
+	// shoot is the Shoot backing shootState; it is nil exactly when shootState is nil.
+	shoot *gardencorev1beta1.Shoot
+}
+
+// resolveOwnerChain walks the Namespace → Cluster → Shoot → ShootState chain that secret sits
+// underneath, so that the caller never mistakes a transient error for a genuine, safe-to-act-on
+// top-down teardown of that chain.
+func (r *reconciler) resolveOwnerChain(ctx context.Context, secret *corev1.Secret) (*ownerChain, error) {
+	namespace := &corev1.Namespace{}
+	if err := r.seedClient.Get(ctx, kutil.Key(secret.Namespace), namespace); err != nil {
+		if apierrors.IsNotFound(err) {
+			return &ownerChain{namespaceExists: false}, nil
+		}
+		return nil, fmt.Errorf("failed to get namespace %q: %w", secret.Namespace, err)
+	}
+
+	if namespace.Labels[v1beta1constants.GardenRole] != v1beta1constants.GardenRoleShoot {
+		return &ownerChain{namespaceExists: true}, nil
+	}
+
+	shootState, shoot, err := extensions.GetShootStateForCluster(ctx, r.gardenClient, r.seedClient, secret.Namespace)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return &ownerChain{namespaceExists: true, isShootNamespace: true}, nil
+		}
+		return nil, fmt.Errorf("failed to resolve ShootState for namespace %q: %w", secret.Namespace, err)
+	}
+
+	return &ownerChain{namespaceExists: true, isShootNamespace: true, shootState: shootState, shoot: shoot}, nil
+}
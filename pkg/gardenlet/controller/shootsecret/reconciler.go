@@ -20,17 +20,12 @@ import (
 	"fmt"
 
 	gardencorev1alpha1 "github.com/gardener/gardener/pkg/apis/core/v1alpha1"
-	gardencorev1alpha1helper "github.com/gardener/gardener/pkg/apis/core/v1alpha1/helper"
 	gardencorev1beta1 "github.com/gardener/gardener/pkg/apis/core/v1beta1"
-	v1beta1constants "github.com/gardener/gardener/pkg/apis/core/v1beta1/constants"
 	"github.com/gardener/gardener/pkg/controllerutils"
-	"github.com/gardener/gardener/pkg/extensions"
-	kutil "github.com/gardener/gardener/pkg/utils/kubernetes"
 
 	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
-	"k8s.io/apimachinery/pkg/runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
@@ -42,13 +37,19 @@ const finalizerName = "gardenlet.gardener.cloud/secret-controller"
 type reconciler struct {
 	gardenClient client.Client
 	seedClient   client.Client
+	envelope     SecretEnvelope
+	batcher      *shootStateBatcher
 }
 
-// NewReconciler returns a new reconciler for secrets related to shoots.
-func NewReconciler(gardenClient, seedClient client.Client) reconcile.Reconciler {
+// NewReconciler returns a new reconciler for secrets related to shoots. Secret data is
+// envelope-encrypted with envelope before being persisted in the ShootState, and ShootState
+// mutations are coalesced through batcher instead of patching once per Secret event.
+func NewReconciler(gardenClient, seedClient client.Client, envelope SecretEnvelope, batcher *shootStateBatcher) reconcile.Reconciler {
 	return &reconciler{
 		gardenClient: gardenClient,
 		seedClient:   seedClient,
+		envelope:     envelope,
+		batcher:      batcher,
 	}
 }
 
@@ -64,32 +65,57 @@ func (r *reconciler) Reconcile(ctx context.Context, request reconcile.Request) (
 		return reconcile.Result{}, fmt.Errorf("error retrieving object from store: %w", err)
 	}
 
-	namespace := &corev1.Namespace{}
-	if err := r.seedClient.Get(ctx, kutil.Key(secret.Namespace), namespace); err != nil {
+	chain, err := r.resolveOwnerChain(ctx, secret)
+	if err != nil {
 		return reconcile.Result{}, err
 	}
-	if namespace.Labels[v1beta1constants.GardenRole] != v1beta1constants.GardenRoleShoot {
+
+	if !chain.namespaceExists {
+		// The owning Namespace (and with it the Cluster/Shoot above the Secret) is already gone.
+		// There is nothing left that could still reference the Secret's data, so fall through to
+		// the same deletion handling used once the Cluster/Shoot/ShootState has been torn down -
+		// otherwise a Secret caught in this state would keep its finalizer forever.
+		if secret.DeletionTimestamp != nil {
+			return r.delete(ctx, log, secret, chain)
+		}
 		return reconcile.Result{}, nil
 	}
 
-	shootState, shoot, err := extensions.GetShootStateForCluster(ctx, r.gardenClient, r.seedClient, secret.Namespace)
-	if err != nil {
-		if apierrors.IsNotFound(err) {
-			if controllerutil.ContainsFinalizer(secret, finalizerName) {
-				log.Info("Removing finalizer")
-				if err := controllerutils.RemoveFinalizers(ctx, r.seedClient, secret, finalizerName); err != nil {
-					return reconcile.Result{}, fmt.Errorf("failed to remove finalizer: %w", err)
-				}
-			}
-			return reconcile.Result{}, nil
-		}
-		return reconcile.Result{}, err
+	if !chain.isShootNamespace {
+		return reconcile.Result{}, nil
 	}
 
 	if secret.DeletionTimestamp != nil {
-		return r.delete(ctx, log, secret, shootState, shoot)
+		return r.delete(ctx, log, secret, chain)
 	}
-	return r.reconcile(ctx, log, secret, shootState)
+
+	// Add the finalizer synchronously, before the Secret's data is ever queued for a batched
+	// ShootState write: the batcher can hold an Upsert for up to a full batch Window before it is
+	// actually patched, and if the Secret were deleted during that window without a finalizer in
+	// place, Kubernetes would remove it immediately, delete() would never run, and the data
+	// already queued would be orphaned in the ShootState forever.
+	if err := r.ensureFinalizer(ctx, log, secret); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	if chain.shootState == nil {
+		log.Info("Shoot's Cluster resource is already gone, nothing to reconcile")
+		return reconcile.Result{}, nil
+	}
+
+	return r.reconcile(ctx, log, secret, chain.shootState)
+}
+
+func (r *reconciler) ensureFinalizer(ctx context.Context, log logr.Logger, secret *corev1.Secret) error {
+	if controllerutil.ContainsFinalizer(secret, finalizerName) {
+		return nil
+	}
+
+	log.Info("Adding finalizer")
+	if err := controllerutils.AddFinalizers(ctx, r.seedClient, secret, finalizerName); err != nil {
+		return fmt.Errorf("failed to add finalizer: %w", err)
+	}
+	return nil
 }
 
 func (r *reconciler) reconcile(
@@ -101,58 +127,60 @@ func (r *reconciler) reconcile(
 	reconcile.Result,
 	error,
 ) {
-	log.Info("Reconciling secret information in ShootState and ensuring its finalizer")
-
-	if !controllerutil.ContainsFinalizer(secret, finalizerName) {
-		log.Info("Adding finalizer")
-		if err := controllerutils.AddFinalizers(ctx, r.seedClient, secret, finalizerName); err != nil {
-			return reconcile.Result{}, fmt.Errorf("failed to add finalizer: %w", err)
-		}
-	}
+	log.Info("Queuing secret information for batched ShootState patch")
 
 	dataJSON, err := json.Marshal(secret.Data)
 	if err != nil {
 		return reconcile.Result{}, err
 	}
 
-	patch := client.StrategicMergeFrom(shootState.DeepCopy())
+	encryptedData, err := encryptSecretData(ctx, r.envelope, dataJSON)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
 
-	dataList := gardencorev1alpha1helper.GardenerResourceDataList(shootState.Spec.Gardener)
-	dataList.Upsert(&gardencorev1alpha1.GardenerResourceData{
+	data := &gardencorev1alpha1.GardenerResourceData{
 		Name:   secret.Name,
 		Labels: secret.Labels,
-		Type:   "secret",
-		Data:   runtime.RawExtension{Raw: dataJSON},
-	})
-	shootState.Spec.Gardener = dataList
+		Type:   secretResourceDataType,
+		Data:   encryptedData,
+	}
 
-	return reconcile.Result{}, r.gardenClient.Patch(ctx, shootState, patch)
+	return reconcile.Result{}, r.batcher.Upsert(ctx, client.ObjectKeyFromObject(shootState), data)
 }
 
+// delete removes secret's data from its ShootState (unless the Shoot is being migrated, in
+// which case the data must survive the move to the destination seed) and only then releases the
+// finalizer, so that a failure patching the ShootState never results in the Secret's data being
+// lost while its finalizer is already gone. If the owner chain shows the Cluster/Shoot has
+// already been torn down, there is no ShootState left to clean up and we only release the
+// finalizer.
 func (r *reconciler) delete(
 	ctx context.Context,
 	log logr.Logger,
 	secret *corev1.Secret,
-	shootState *gardencorev1alpha1.ShootState,
-	shoot *gardencorev1beta1.Shoot,
+	chain *ownerChain,
 ) (
 	reconcile.Result,
 	error,
 ) {
-	if lastOp := shoot.Status.LastOperation; lastOp != nil && lastOp.Type == gardencorev1beta1.LastOperationTypeMigrate {
-		log.Info("Keeping Secret in ShootState since Shoot is in migration but releasing the finalizer")
-	} else {
-		log.Info("Removing Secret from ShootState and releasing its finalizer")
+	switch {
+	case chain.shootState == nil:
+		log.Info("Owning Cluster/Shoot is already gone, skipping ShootState cleanup")
 
-		patch := client.StrategicMergeFrom(shootState.DeepCopy())
+	case chain.shoot.Status.LastOperation != nil && chain.shoot.Status.LastOperation.Type == gardencorev1beta1.LastOperationTypeMigrate:
+		log.Info("Keeping Secret in ShootState since Shoot is in migration but releasing the finalizer")
 
-		dataList := gardencorev1alpha1helper.GardenerResourceDataList(shootState.Spec.Gardener)
-		dataList.Delete(secret.Name)
-		shootState.Spec.Gardener = dataList
+	default:
+		log.Info("Queuing Secret removal from ShootState")
 
-		if err := r.gardenClient.Patch(ctx, shootState, patch); err != nil {
-			return reconcile.Result{}, err
+		// The batcher only releases the finalizer once this removal has actually been patched,
+		// so it is safe to return directly instead of falling through to the immediate
+		// finalizer removal below.
+		if err := r.batcher.Delete(ctx, client.ObjectKeyFromObject(chain.shootState), secret, secret.Name); err != nil {
+			return reconcile.Result{}, fmt.Errorf("failed to remove secret data from ShootState: %w", err)
 		}
+		return reconcile.Result{}, nil
 	}
 
 	if controllerutil.ContainsFinalizer(secret, finalizerName) {
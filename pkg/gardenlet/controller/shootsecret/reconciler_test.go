@@ -0,0 +1,160 @@
+// Copyright (c) 2022 SAP SE or an SAP affiliate company. All rights reserved. This file is licensed under the Apache Software License, v. 2 except as noted otherwise in the LICENSE file
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shootsecret
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	gardencorev1alpha1 "github.com/gardener/gardener/pkg/apis/core/v1alpha1"
+	gardencorev1beta1 "github.com/gardener/gardener/pkg/apis/core/v1beta1"
+	"github.com/gardener/gardener/pkg/client/kubernetes"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("reconciler#delete", func() {
+	var (
+		ctx context.Context
+
+		secret     *corev1.Secret
+		shoot      *gardencorev1beta1.Shoot
+		shootState *gardencorev1alpha1.ShootState
+		chain      *ownerChain
+
+		seedClient client.WithWatch
+		envelope   SecretEnvelope
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+
+		var err error
+		envelope, err = NewAESGCMEnvelope("key-1", map[string][]byte{"key-1": make([]byte, 32)})
+		Expect(err).NotTo(HaveOccurred())
+
+		encrypted, err := encryptSecretData(ctx, envelope, []byte(`{"foo":"bar"}`))
+		Expect(err).NotTo(HaveOccurred())
+
+		now := metav1.Now()
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "my-secret",
+				Namespace:         "shoot--foo--bar",
+				Finalizers:        []string{finalizerName},
+				DeletionTimestamp: &now,
+			},
+		}
+
+		shoot = &gardencorev1beta1.Shoot{ObjectMeta: metav1.ObjectMeta{Name: "bar", Namespace: "garden-foo"}}
+
+		shootState = &gardencorev1alpha1.ShootState{
+			ObjectMeta: metav1.ObjectMeta{Name: "bar", Namespace: "garden-foo"},
+			Spec: gardencorev1alpha1.ShootStateSpec{
+				Gardener: []gardencorev1alpha1.GardenerResourceData{
+					{Name: secret.Name, Type: secretResourceDataType, Data: encrypted},
+				},
+			},
+		}
+
+		chain = &ownerChain{namespaceExists: true, isShootNamespace: true, shootState: shootState, shoot: shoot}
+
+		seedClient = fake.NewClientBuilder().WithScheme(kubernetes.SeedScheme).WithObjects(secret).Build()
+	})
+
+	It("should not lose the ShootState removal and not strand the finalizer when the finalizer patch fails transiently", func() {
+		gardenClient := fake.NewClientBuilder().WithScheme(kubernetes.GardenScheme).WithObjects(shootState).Build()
+
+		var patchAttempts int
+		failingSeedClient := interceptor.NewClient(seedClient, interceptor.Funcs{
+			Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+				patchAttempts++
+				if patchAttempts == 1 {
+					return apierrors.NewInternalError(fmt.Errorf("boom"))
+				}
+				return c.Patch(ctx, obj, patch, opts...)
+			},
+		})
+
+		batcher := NewShootStateBatcher(gardenClient, failingSeedClient, 10*time.Millisecond)
+		r := &reconciler{gardenClient: gardenClient, seedClient: failingSeedClient, envelope: envelope, batcher: batcher}
+
+		fetchSecret := func() *corev1.Secret {
+			s := &corev1.Secret{}
+			Expect(seedClient.Get(ctx, client.ObjectKeyFromObject(secret), s)).To(Succeed())
+			return s
+		}
+
+		_, err := r.delete(ctx, logr.Discard(), fetchSecret(), chain)
+		Expect(err).To(HaveOccurred())
+
+		persistedShootState := &gardencorev1alpha1.ShootState{}
+		Expect(gardenClient.Get(ctx, client.ObjectKeyFromObject(shootState), persistedShootState)).To(Succeed())
+		Expect(persistedShootState.Spec.Gardener).To(BeEmpty(), "the already-successful ShootState patch must not be lost or retried")
+
+		Expect(controllerutil.ContainsFinalizer(fetchSecret(), finalizerName)).To(BeTrue(), "finalizer must not be stuck as removed when patching it failed")
+
+		_, err = r.delete(ctx, logr.Discard(), fetchSecret(), chain)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(controllerutil.ContainsFinalizer(fetchSecret(), finalizerName)).To(BeFalse())
+	})
+})
+
+var _ = Describe("reconciler#Reconcile", func() {
+	It("should release the finalizer instead of getting stuck when the owning Namespace is already gone", func() {
+		ctx := context.Background()
+
+		now := metav1.Now()
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "my-secret",
+				Namespace:         "shoot--foo--bar",
+				Finalizers:        []string{finalizerName},
+				DeletionTimestamp: &now,
+			},
+		}
+
+		// Deliberately no Namespace object: the owning Namespace (and with it the Cluster/Shoot
+		// above it) has already been torn down.
+		seedClient := fake.NewClientBuilder().WithScheme(kubernetes.SeedScheme).WithObjects(secret).Build()
+		gardenClient := fake.NewClientBuilder().WithScheme(kubernetes.GardenScheme).Build()
+
+		envelope, err := NewAESGCMEnvelope("key-1", map[string][]byte{"key-1": make([]byte, 32)})
+		Expect(err).NotTo(HaveOccurred())
+
+		batcher := NewShootStateBatcher(gardenClient, seedClient, 10*time.Millisecond)
+		r := NewReconciler(gardenClient, seedClient, envelope, batcher)
+
+		_, err = r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(secret)})
+		Expect(err).NotTo(HaveOccurred())
+
+		persisted := &corev1.Secret{}
+		Expect(seedClient.Get(ctx, client.ObjectKeyFromObject(secret), persisted)).To(Succeed())
+		Expect(controllerutil.ContainsFinalizer(persisted, finalizerName)).To(BeFalse(),
+			"the finalizer must be released once the owning Namespace is gone, not stuck forever")
+	})
+})
@@ -0,0 +1,124 @@
+// Copyright (c) 2022 SAP SE or an SAP affiliate company. All rights reserved. This file is licensed under the Apache Software License, v. 2 except as noted otherwise in the LICENSE file
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shootsecret
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	gardencorev1alpha1 "github.com/gardener/gardener/pkg/apis/core/v1alpha1"
+	gardencorev1alpha1helper "github.com/gardener/gardener/pkg/apis/core/v1alpha1/helper"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// KeyRotator periodically walks all ShootStates and re-encrypts any "secret" GardenerResourceData
+// entry whose stored key id no longer matches Envelope.CurrentKeyID(). It implements
+// manager.Runnable, so that it can run in the manager alongside the reconciler rather than being
+// triggered by watch events, since rotating a key does not by itself generate any event on the
+// Secret or ShootState. This package does not add it to a manager itself.
+type KeyRotator struct {
+	GardenClient client.Client
+	Envelope     SecretEnvelope
+	// Interval is the time between two rotation sweeps.
+	Interval time.Duration
+}
+
+// Start implements manager.Runnable.
+func (k *KeyRotator) Start(ctx context.Context) error {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := k.Rotate(ctx); err != nil {
+			logf.FromContext(ctx).Error(err, "Failed to rotate shoot secret keys")
+		}
+	}, k.Interval)
+
+	return nil
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable. Rotation must not run
+// concurrently from multiple gardenlet replicas, since two replicas racing to re-encrypt the
+// same entry would each read a (possibly stale) ShootState and could clobber one another's
+// patch.
+func (k *KeyRotator) NeedLeaderElection() bool {
+	return true
+}
+
+// Rotate performs a single sweep over all ShootStates, re-encrypting any "secret"
+// GardenerResourceData entry whose key id is no longer current.
+func (k *KeyRotator) Rotate(ctx context.Context) error {
+	shootStates := &gardencorev1alpha1.ShootStateList{}
+	if err := k.GardenClient.List(ctx, shootStates); err != nil {
+		return fmt.Errorf("failed to list ShootStates: %w", err)
+	}
+
+	log := logf.FromContext(ctx)
+	for i := range shootStates.Items {
+		shootState := &shootStates.Items[i]
+		if err := k.rotateShootState(ctx, shootState); err != nil {
+			log.Error(err, "Failed to rotate keys for ShootState", "shootState", client.ObjectKeyFromObject(shootState))
+		}
+	}
+
+	return nil
+}
+
+// rotateShootState re-encrypts every "secret" GardenerResourceData entry of shootState that was
+// encrypted under a key id other than the envelope's current one, and patches the result back if
+// anything changed.
+func (k *KeyRotator) rotateShootState(ctx context.Context, shootState *gardencorev1alpha1.ShootState) error {
+	patch := client.StrategicMergeFrom(shootState.DeepCopy())
+
+	dataList := gardencorev1alpha1helper.GardenerResourceDataList(shootState.Spec.Gardener)
+	var changed bool
+
+	for i, entry := range dataList {
+		if entry.Type != secretResourceDataType {
+			continue
+		}
+
+		payload := &EncryptedSecretData{}
+		if err := json.Unmarshal(entry.Data.Raw, payload); err != nil {
+			return fmt.Errorf("failed to unmarshal encrypted secret data %q: %w", entry.Name, err)
+		}
+
+		if payload.KeyID == k.Envelope.CurrentKeyID() {
+			continue
+		}
+
+		plaintext, err := k.Envelope.Decrypt(ctx, payload)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt secret data %q with key id %q: %w", entry.Name, payload.KeyID, err)
+		}
+
+		reencrypted, err := encryptSecretData(ctx, k.Envelope, plaintext)
+		if err != nil {
+			return fmt.Errorf("failed to re-encrypt secret data %q: %w", entry.Name, err)
+		}
+
+		dataList[i].Data = reencrypted
+		changed = true
+	}
+
+	if !changed {
+		return nil
+	}
+
+	shootState.Spec.Gardener = dataList
+	return k.GardenClient.Patch(ctx, shootState, patch)
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2022 SAP SE or an SAP affiliate company. All rights reserved. This file is licensed under the Apache Software License, v. 2 except as noted otherwise in the LICENSE file
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shootsecret_test
+
+import (
+	"context"
+	"encoding/json"
+
+	gardencorev1alpha1 "github.com/gardener/gardener/pkg/apis/core/v1alpha1"
+	"github.com/gardener/gardener/pkg/client/kubernetes"
+	. "github.com/gardener/gardener/pkg/gardenlet/controller/shootsecret"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("KeyRotator", func() {
+	var (
+		ctx          context.Context
+		gardenClient client.Client
+		oldKey       = make([]byte, 32)
+		newKey       = func() []byte { k := make([]byte, 32); k[0] = 1; return k }()
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		gardenClient = fakeclient.NewClientBuilder().WithScheme(kubernetes.GardenScheme).Build()
+	})
+
+	It("should re-encrypt entries stored under an old key and leave up-to-date entries untouched", func() {
+		oldEnvelope, err := NewAESGCMEnvelope("key-1", map[string][]byte{"key-1": oldKey})
+		Expect(err).NotTo(HaveOccurred())
+
+		stalePayload, err := oldEnvelope.Encrypt(ctx, []byte(`{"foo":"bar"}`))
+		Expect(err).NotTo(HaveOccurred())
+		stalePayloadJSON, err := json.Marshal(stalePayload)
+		Expect(err).NotTo(HaveOccurred())
+
+		rotatedEnvelope, err := NewAESGCMEnvelope("key-2", map[string][]byte{"key-1": oldKey, "key-2": newKey})
+		Expect(err).NotTo(HaveOccurred())
+
+		upToDatePayload, err := rotatedEnvelope.Encrypt(ctx, []byte(`{"already":"current"}`))
+		Expect(err).NotTo(HaveOccurred())
+		upToDatePayloadJSON, err := json.Marshal(upToDatePayload)
+		Expect(err).NotTo(HaveOccurred())
+
+		shootState := &gardencorev1alpha1.ShootState{
+			ObjectMeta: metav1.ObjectMeta{Name: "shoot", Namespace: "garden-foo"},
+			Spec: gardencorev1alpha1.ShootStateSpec{
+				Gardener: []gardencorev1alpha1.GardenerResourceData{
+					{Name: "stale-secret", Type: "secret", Data: runtime.RawExtension{Raw: stalePayloadJSON}},
+					{Name: "current-secret", Type: "secret", Data: runtime.RawExtension{Raw: upToDatePayloadJSON}},
+				},
+			},
+		}
+		Expect(gardenClient.Create(ctx, shootState)).To(Succeed())
+
+		rotator := &KeyRotator{GardenClient: gardenClient, Envelope: rotatedEnvelope}
+		Expect(rotator.Rotate(ctx)).To(Succeed())
+
+		persisted := &gardencorev1alpha1.ShootState{}
+		Expect(gardenClient.Get(ctx, client.ObjectKeyFromObject(shootState), persisted)).To(Succeed())
+
+		var stale, current *gardencorev1alpha1.GardenerResourceData
+		for i := range persisted.Spec.Gardener {
+			switch persisted.Spec.Gardener[i].Name {
+			case "stale-secret":
+				stale = &persisted.Spec.Gardener[i]
+			case "current-secret":
+				current = &persisted.Spec.Gardener[i]
+			}
+		}
+		Expect(stale).NotTo(BeNil())
+		Expect(current).NotTo(BeNil())
+
+		plaintext, err := DecryptGardenerResourceData(ctx, rotatedEnvelope, stale)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(plaintext).To(Equal([]byte(`{"foo":"bar"}`)))
+
+		Expect(current.Data.Raw).To(Equal(upToDatePayloadJSON), "already up-to-date entry should not be rewritten")
+	})
+})
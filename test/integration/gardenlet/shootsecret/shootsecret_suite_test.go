@@ -0,0 +1,128 @@
+// Copyright (c) 2022 SAP SE or an SAP affiliate company. All rights reserved. This file is licensed under the Apache Software License, v. 2 except as noted otherwise in the LICENSE file
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package shootsecret_test runs the shootsecret controller's Reconcile against a real seed API
+// server started via envtest, instead of client/fake, so that the finalizer/watch races this
+// controller is meant to survive are exercised under genuine concurrent Secret events rather than
+// the single-threaded, synchronous semantics client/fake gives every call.
+//
+// The garden-side ShootState/Shoot are served by a fake client: those are aggregated
+// core.gardener.cloud types that need the Gardener API server add-on to run for real, which is
+// out of scope here - the batcher's coalescing/patching logic is covered, against client/fake, by
+// the unit suite in pkg/gardenlet/controller/shootsecret. What this suite adds
+// is coverage of the finalizer add/remove race against a real Secret create/update/delete storm.
+package shootsecret_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	logzap "sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	gardencorev1alpha1 "github.com/gardener/gardener/pkg/apis/core/v1alpha1"
+	gardencorev1beta1 "github.com/gardener/gardener/pkg/apis/core/v1beta1"
+	v1beta1constants "github.com/gardener/gardener/pkg/apis/core/v1beta1/constants"
+	"github.com/gardener/gardener/pkg/client/kubernetes"
+	"github.com/gardener/gardener/pkg/gardenlet/controller/shootsecret"
+)
+
+func TestShootSecret(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Shootsecret Controller Integration Suite")
+}
+
+const testID = "shootsecret-controller-test"
+
+var (
+	ctx = context.Background()
+	log logr.Logger
+
+	testEnv   *envtest.Environment
+	mgrCancel context.CancelFunc
+
+	seedClient   client.Client
+	gardenClient client.Client
+
+	shootNamespace *corev1.Namespace
+	shootState     *gardencorev1alpha1.ShootState
+)
+
+var _ = BeforeSuite(func() {
+	logf.SetLogger(logzap.New(logzap.WriteTo(GinkgoWriter), logzap.UseDevMode(true)))
+	log = logf.Log.WithName(testID)
+
+	By("starting seed test environment")
+	testEnv = &envtest.Environment{}
+
+	restConfig, err := testEnv.Start()
+	Expect(err).NotTo(HaveOccurred())
+	DeferCleanup(func() {
+		By("stopping seed test environment")
+		Expect(testEnv.Stop()).To(Succeed())
+	})
+
+	seedClient, err = client.New(restConfig, client.Options{Scheme: kubernetes.SeedScheme})
+	Expect(err).NotTo(HaveOccurred())
+
+	By("creating shoot namespace")
+	shootNamespace = &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		GenerateName: "shoot--" + testID + "--",
+		Labels:       map[string]string{v1beta1constants.GardenRole: v1beta1constants.GardenRoleShoot},
+	}}
+	Expect(seedClient.Create(ctx, shootNamespace)).To(Succeed())
+	DeferCleanup(func() {
+		Expect(client.IgnoreNotFound(seedClient.Delete(ctx, shootNamespace))).To(Succeed())
+	})
+
+	By("faking the garden-side ShootState this Shoot's Secrets are persisted into")
+	shoot := &gardencorev1beta1.Shoot{ObjectMeta: metav1.ObjectMeta{Name: "storm", Namespace: "garden-" + testID}}
+	shootState = &gardencorev1alpha1.ShootState{ObjectMeta: metav1.ObjectMeta{Name: shoot.Name, Namespace: shoot.Namespace}}
+	gardenClient = fake.NewClientBuilder().WithScheme(kubernetes.GardenScheme).WithObjects(shoot, shootState).Build()
+
+	By("starting manager")
+	mgr, err := manager.New(restConfig, manager.Options{Scheme: kubernetes.SeedScheme})
+	Expect(err).NotTo(HaveOccurred())
+
+	envelope, err := shootsecret.NewAESGCMEnvelope("test-key", map[string][]byte{"test-key": make([]byte, 32)})
+	Expect(err).NotTo(HaveOccurred())
+
+	batcher := shootsecret.NewShootStateBatcher(gardenClient, seedClient, 200*time.Millisecond)
+	reconciler := shootsecret.NewReconciler(gardenClient, seedClient, envelope, batcher)
+
+	Expect(builder.ControllerManagedBy(mgr).
+		For(&corev1.Secret{}).
+		Complete(reconciler)).To(Succeed())
+
+	var mgrCtx context.Context
+	mgrCtx, mgrCancel = context.WithCancel(ctx)
+	go func() {
+		defer GinkgoRecover()
+		Expect(mgr.Start(mgrCtx)).To(Succeed())
+	}()
+	DeferCleanup(func() {
+		mgrCancel()
+	})
+})
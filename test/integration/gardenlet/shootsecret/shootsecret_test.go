@@ -0,0 +1,117 @@
+// Copyright (c) 2022 SAP SE or an SAP affiliate company. All rights reserved. This file is licensed under the Apache Software License, v. 2 except as noted otherwise in the LICENSE file
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shootsecret_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	gardencorev1alpha1 "github.com/gardener/gardener/pkg/apis/core/v1alpha1"
+)
+
+var _ = Describe("Shootsecret controller", func() {
+	It("should survive a concurrent create/update/delete storm without stranding finalizers or ShootState entries", func() {
+		const secretCount = 10
+
+		names := make([]string, secretCount)
+		for i := range names {
+			names[i] = fmt.Sprintf("storm-secret-%d", i)
+		}
+
+		By("creating secrets concurrently")
+		var wg sync.WaitGroup
+		for _, name := range names {
+			name := name
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+
+				Expect(seedClient.Create(ctx, &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: shootNamespace.Name},
+					Data:       map[string][]byte{"foo": []byte("bar-0")},
+				})).To(Succeed())
+			}()
+		}
+		wg.Wait()
+
+		By("updating and deleting secrets concurrently")
+		for round := 1; round <= 3; round++ {
+			round := round
+			for _, name := range names {
+				name := name
+				wg.Add(1)
+				go func() {
+					defer GinkgoRecover()
+					defer wg.Done()
+
+					secret := &corev1.Secret{}
+					if err := seedClient.Get(ctx, client.ObjectKey{Name: name, Namespace: shootNamespace.Name}, secret); err != nil {
+						return
+					}
+					secret.Data = map[string][]byte{"foo": []byte(fmt.Sprintf("bar-%d", round))}
+					_ = seedClient.Update(ctx, secret)
+				}()
+			}
+			wg.Wait()
+		}
+
+		for _, name := range names {
+			name := name
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+
+				secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: shootNamespace.Name}}
+				Expect(client.IgnoreNotFound(seedClient.Delete(ctx, secret))).To(Succeed())
+			}()
+		}
+		wg.Wait()
+
+		By("waiting for every secret to be gone, with its finalizer released")
+		Eventually(func() error {
+			for _, name := range names {
+				secret := &corev1.Secret{}
+				err := seedClient.Get(ctx, client.ObjectKey{Name: name, Namespace: shootNamespace.Name}, secret)
+				if err == nil {
+					return fmt.Errorf("secret %q still exists with finalizers %v", name, secret.Finalizers)
+				}
+				if !apierrors.IsNotFound(err) {
+					return err
+				}
+			}
+			return nil
+		}, 30*time.Second, 100*time.Millisecond).Should(Succeed())
+
+		By("verifying no secret data was stranded in the ShootState")
+		persistedShootState := &gardencorev1alpha1.ShootState{}
+		Expect(gardenClient.Get(context.Background(), client.ObjectKeyFromObject(shootState), persistedShootState)).To(Succeed())
+		for _, data := range persistedShootState.Spec.Gardener {
+			for _, name := range names {
+				Expect(data.Name).NotTo(Equal(name), "secret %q's data should have been removed from the ShootState once deleted", name)
+			}
+		}
+	})
+})